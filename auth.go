@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultJWTTTL = 15 * time.Minute
+
+// authHandlers exposes the authentication endpoints: currently just
+// POST /auth/login.
+type authHandlers struct {
+	store  Store
+	secret []byte
+	ttl    time.Duration
+}
+
+// newAuthHandlers reads JWT_SECRET (required) and JWT_TTL_MINUTES
+// (optional, default 15) from the environment.
+func newAuthHandlers(store Store) (*authHandlers, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("need to set env variable for JWT_SECRET")
+	}
+
+	ttl := defaultJWTTTL
+	if raw := os.Getenv("JWT_TTL_MINUTES"); raw != "" {
+		minutes, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_TTL_MINUTES: %w", err)
+		}
+		ttl = time.Duration(minutes) * time.Minute
+	}
+
+	return &authHandlers{store: store, secret: []byte(secret), ttl: ttl}, nil
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+// login validates credentials against the store and, on success, returns a
+// signed JWT identifying the user.
+func (a *authHandlers) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		newResponse(w, r).WithStatus(http.StatusMethodNotAllowed).WithHeader("allow", "POST").
+			Error(ErrCodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	var req loginRequest
+	if err := Decode(r, &req); err != nil {
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			newResponse(w, r).WithStatus(http.StatusUnsupportedMediaType).Error(ErrCodeInvalidContentType, err.Error(), nil)
+			return
+		}
+		newResponse(w, r).WithStatus(http.StatusBadRequest).Error(ErrCodeBadRequest, err.Error(), nil)
+		return
+	}
+
+	user, err := a.store.GetUser(req.Username)
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		newResponse(w, r).WithStatus(http.StatusUnauthorized).Error(ErrCodeUnauthorized, "invalid username or password", nil)
+		return
+	}
+
+	token, err := newJWT(a.secret, user.Username, a.ttl)
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+
+	newResponse(w, r).WithStatus(http.StatusOK).JSON(loginResponse{Token: token})
+}
+
+// bootstrapAdminUser ensures an initial admin account exists, reading
+// ADMIN_USERNAME (default "admin") and ADMIN_PASSWORD (required) from the
+// environment. It is a no-op if the account already exists.
+func bootstrapAdminUser(store Store) error {
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		return fmt.Errorf("need to set env variable for ADMIN_PASSWORD")
+	}
+	username := os.Getenv("ADMIN_USERNAME")
+	if username == "" {
+		username = "admin"
+	}
+
+	if _, err := store.GetUser(username); err == nil {
+		return nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return store.PutUser(User{Username: username, PasswordHash: string(hash), Role: RoleAdmin})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedMediaType is returned by Decode when the request's
+// Content-Type isn't one this API understands.
+var ErrUnsupportedMediaType = errors.New("unsupported content type")
+
+// ErrNotAcceptable is returned by Respond when none of the client's
+// Accept types can be satisfied.
+var ErrNotAcceptable = errors.New("not acceptable")
+
+// supportedRespondTypes are the response types each Accept entry is
+// matched against.
+var supportedRespondTypes = []string{"application/json", "application/xml", "text/xml"}
+
+// Decode reads r's body into v according to its Content-Type, supporting
+// application/json, application/xml, text/xml and
+// application/x-www-form-urlencoded. It returns ErrUnsupportedMediaType for
+// any other Content-Type.
+func Decode(r *http.Request, v interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	switch mediaType(r.Header.Get("content-type")) {
+	case "application/json":
+		return json.Unmarshal(body, v)
+	case "application/xml", "text/xml":
+		return xml.Unmarshal(body, v)
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return err
+		}
+		return decodeForm(values, v)
+	default:
+		return ErrUnsupportedMediaType
+	}
+}
+
+// Respond writes v to w as status, encoded according to r's Accept header.
+// It returns ErrNotAcceptable if none of the client's preferences can be
+// satisfied.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	ct, err := negotiateAccept(r.Header.Get("accept"))
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	switch ct {
+	case "application/xml", "text/xml":
+		body, err = xml.Marshal(v)
+	default:
+		body, err = json.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("content-type", ct)
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	return err
+}
+
+// mediaType strips any parameters (e.g. "; charset=utf-8") from a
+// Content-Type header value.
+func mediaType(contentType string) string {
+	return strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+}
+
+// negotiateAccept walks the client's Accept entries in the order listed
+// and picks the first one this API supports; q-values are ignored. An
+// empty header, "*/*" or "application/*" all resolve to JSON.
+func negotiateAccept(accept string) (string, error) {
+	if accept == "" {
+		return "application/json", nil
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mt := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if mt == "*/*" || mt == "application/*" {
+			return "application/json", nil
+		}
+		for _, supported := range supportedRespondTypes {
+			if mt == supported {
+				return supported, nil
+			}
+		}
+	}
+	return "", ErrNotAcceptable
+}
+
+// decodeForm binds url.Values into the struct pointed to by v, matching
+// form field names against each field's `json` tag the way common Go web
+// binders bind query and form parameters.
+func decodeForm(values url.Values, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("encoding: Decode target must be a pointer to a struct")
+	}
+
+	elem := rv.Elem()
+	t := elem.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+
+		fv := elem.Field(i)
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(raw)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return fmt.Errorf("encoding: field %q: %w", name, err)
+			}
+			fv.SetInt(n)
+		default:
+			return fmt.Errorf("encoding: unsupported field kind %s for %q", fv.Kind(), name)
+		}
+	}
+	return nil
+}
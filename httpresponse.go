@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+)
+
+// Error codes used in the "code" field of errorBody across every handler.
+// Clients should switch on these, not on the message text.
+const (
+	ErrCodeBadRequest         = "bad_request"
+	ErrCodeInvalidContentType = "invalid_content_type"
+	ErrCodeInvalidQuery       = "invalid_query"
+	ErrCodeNotAcceptable      = "not_acceptable"
+	ErrCodeNotFound           = "not_found"
+	ErrCodePreconditionFailed = "precondition_failed"
+	ErrCodeUnauthorized       = "unauthorized"
+	ErrCodeForbidden          = "forbidden"
+	ErrCodeMethodNotAllowed   = "method_not_allowed"
+	ErrCodeInternal           = "internal_error"
+)
+
+// errorBody is the canonical error shape every handler in this API returns:
+// {"error":{"code":"...","message":"...","details":{...}}}.
+type errorBody struct {
+	XMLName xml.Name    `json:"-" xml:"error"`
+	Error   errorDetail `json:"error" xml:"error"`
+}
+
+type errorDetail struct {
+	Code    string            `json:"code" xml:"code"`
+	Message string            `json:"message" xml:"message"`
+	Details map[string]string `json:"details,omitempty" xml:"details,omitempty"`
+}
+
+// response is a fluent builder over Respond: newResponse(w, r).
+// WithStatus(...).WithHeader(...).JSON(v) or .Error(...). It exists so every
+// handler produces the same status/header/body plumbing and the same
+// error shape instead of each writing WriteHeader/Write by hand.
+type response struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	status int
+}
+
+// newResponse starts a builder for w/r, defaulting to 200 OK.
+func newResponse(w http.ResponseWriter, r *http.Request) *response {
+	return &response{w: w, r: r, status: http.StatusOK}
+}
+
+// WithStatus sets the status code the response is eventually written with.
+func (b *response) WithStatus(status int) *response {
+	b.status = status
+	return b
+}
+
+// WithHeader sets a response header before the body is written.
+func (b *response) WithHeader(key, value string) *response {
+	b.w.Header().Set(key, value)
+	return b
+}
+
+// JSON writes v as the response body, content-negotiated per the request's
+// Accept header (see Respond in encoding.go). If Respond fails, most often
+// because no Accept entry can be satisfied, it writes a 406 carrying the
+// canonical error body with code ErrCodeNotAcceptable, encoded as JSON.
+func (b *response) JSON(v interface{}) {
+	if err := Respond(b.w, b.r, b.status, v); err != nil {
+		body, _ := json.Marshal(errorBody{Error: errorDetail{Code: ErrCodeNotAcceptable, Message: err.Error()}})
+		b.w.Header().Set("content-type", "application/json")
+		b.w.WriteHeader(http.StatusNotAcceptable)
+		b.w.Write(body)
+	}
+}
+
+// Error writes the canonical {error:{code,message,details}} body at the
+// builder's configured status.
+func (b *response) Error(code, message string, details map[string]string) {
+	b.JSON(errorBody{Error: errorDetail{Code: code, Message: message, Details: details}})
+}
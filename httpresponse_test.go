@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseError(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/coasters/1", nil)
+
+	newResponse(w, r).WithStatus(http.StatusNotFound).Error(ErrCodeNotFound, "no content found for ID, '1'", nil)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	var body errorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if body.Error.Code != ErrCodeNotFound || body.Error.Message != "no content found for ID, '1'" {
+		t.Fatalf("Error = %+v, want code %q", body.Error, ErrCodeNotFound)
+	}
+}
+
+// TestHandlerErrorContract exercises each failure mode below against the
+// real handlers and checks that they all share the canonical
+// {"error":{"code","message"}} shape rather than a bespoke body.
+func TestHandlerErrorContract(t *testing.T) {
+	h := newCoasterHandlers(newMemoryStore(), false)
+
+	cases := []struct {
+		name       string
+		req        *http.Request
+		handle     func(w http.ResponseWriter, r *http.Request)
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "get missing coaster",
+			req:        httptest.NewRequest(http.MethodGet, "/coasters/missing", nil),
+			handle:     h.coaster,
+			wantStatus: http.StatusNotFound,
+			wantCode:   ErrCodeNotFound,
+		},
+		{
+			name:       "post unsupported content type",
+			req:        httptest.NewRequest(http.MethodPost, "/coasters", nil),
+			handle:     h.post,
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantCode:   ErrCodeInvalidContentType,
+		},
+		{
+			name:       "invalid query",
+			req:        httptest.NewRequest(http.MethodGet, "/coasters?sort=length", nil),
+			handle:     h.coasters,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrCodeInvalidQuery,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.handle(w, tc.req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tc.wantStatus)
+			}
+			var body errorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Unmarshal: %v, body = %s", err, w.Body.String())
+			}
+			if body.Error.Code != tc.wantCode {
+				t.Fatalf("Error.Code = %q, want %q", body.Error.Code, tc.wantCode)
+			}
+		})
+	}
+}
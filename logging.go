@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// requestIDHeader is the header clients may set to propagate a request ID
+// across service boundaries; LoggingMiddleware generates one when absent.
+const requestIDHeader = "X-Request-ID"
+
+// statusRecorder wraps a ResponseWriter to capture the status code a
+// handler wrote, since http.ResponseWriter has no getter for it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// accessLogEntry is the structured line LoggingMiddleware emits per request.
+type accessLogEntry struct {
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Status     int     `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	RequestID  string  `json:"request_id"`
+}
+
+// LoggingMiddleware assigns (or propagates, via requestIDHeader) a request
+// ID and logs one structured JSON line per request with its method, path,
+// status and duration.
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		duration := time.Since(start)
+
+		entry := accessLogEntry{
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     rec.status,
+			DurationMS: float64(duration) / float64(time.Millisecond),
+			RequestID:  requestID,
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			log.Printf("logging: marshal access log: %v", err)
+			return
+		}
+		log.Println(string(line))
+	})
+}
+
+// newRequestID generates a short random hex ID for requests that don't
+// already carry one.
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
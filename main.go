@@ -3,192 +3,502 @@
 //  + GET /coasters/{id} returns details of specific coaster as JSON
 //  + POST /coasters accepts a new coaster to be added
 //  + POST /coasters returns status 415 if content is not application/json
-//  + GET /admin requires basic auth
 //  GET /coasters/random redirects (Status 302) to a random coaster
+//  + PUT /coasters/{id} replaces a coaster, PATCH merges fields, DELETE removes it
+//  + PUT/PATCH honor If-Match against the resource's ETag (412 on mismatch)
+//  + GET honors If-None-Match and returns 304 when the ETag is unchanged
+//  + Request bodies and responses negotiate JSON, XML and form encoding
+//    (see encoding.go) based on Content-Type/Accept
+//  + POST /auth/login exchanges credentials for a JWT (see auth.go)
+//  + /admin and mutating coaster endpoints require RoleAdmin; reads are
+//    public unless COASTERS_REQUIRE_READ_AUTH=true, which restricts them to
+//    RoleUser or RoleAdmin (see middleware.go)
+//  + Every error response shares one JSON/XML shape,
+//    {"error":{"code","message","details"}}, built by response.Error
+//    (see httpresponse.go)
+//  + Every route is wrapped in structured JSON access logging and
+//    Prometheus metrics exposed at /metrics (see logging.go, metrics.go);
+//    the server drains in-flight requests on SIGINT/SIGTERM (see server.go)
 
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
 type coaster struct {
-	Name       string `json:"name"`
-	Manufactur string `json:"manufactur"`
-	ID         string `json:"id"`
-	InPark     string `json:"inPark"`
-	Height     int    `json:"height"`
+	XMLName    xml.Name `json:"-" xml:"coaster"`
+	Name       string   `json:"name" xml:"name"`
+	Manufactur string   `json:"manufactur" xml:"manufactur"`
+	ID         string   `json:"id" xml:"id"`
+	InPark     string   `json:"inPark" xml:"inPark"`
+	Height     int      `json:"height" xml:"height"`
+}
+
+// etag returns the coaster's ETag, quoted per RFC 7232, derived from the
+// SHA-256 of its canonical JSON representation.
+func etag(c coaster) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return fmt.Sprintf("%q", fmt.Sprintf("%x", sum)), nil
 }
 
 type coasterHandlers struct {
 	sync.Mutex
-	store map[string]coaster
+	store Store
+	// requireReadAuth gates GET requests behind RoleUser/RoleAdmin; when
+	// false, reads stay public while writes still require RoleAdmin.
+	requireReadAuth bool
 }
 
 func (h *coasterHandlers) coasters(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
-	case "GET":
+	case http.MethodGet:
+		if h.requireReadAuth && !hasRole(r, RoleUser) && !hasRole(r, RoleAdmin) {
+			forbidden(w, r)
+			return
+		}
 		h.get(w, r)
 		return
-	case "POST":
+	case http.MethodPost:
+		if !hasRole(r, RoleAdmin) {
+			forbidden(w, r)
+			return
+		}
 		h.post(w, r)
 		return
 	default:
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		w.Write([]byte("Method not allowed"))
+		newResponse(w, r).WithStatus(http.StatusMethodNotAllowed).WithHeader("allow", "GET, POST").
+			Error(ErrCodeMethodNotAllowed, "method not allowed", nil)
 	}
 }
-func (h *coasterHandlers) post(w http.ResponseWriter, r *http.Request) {
-	bodyBytes, err := ioutil.ReadAll(r.Body)
-	defer r.Body.Close()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-	}
-
-	ct := r.Header.Get("content-type")
-	if ct != "application/json" {
-		w.WriteHeader(http.StatusUnsupportedMediaType)
-		w.Write([]byte(fmt.Sprintf("Need type of application/json but got '%s'", ct)))
-	}
 
+func (h *coasterHandlers) post(w http.ResponseWriter, r *http.Request) {
 	var coasterBody coaster
-	err = json.Unmarshal(bodyBytes, &coasterBody)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
+	if err := Decode(r, &coasterBody); err != nil {
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			newResponse(w, r).WithStatus(http.StatusUnsupportedMediaType).Error(ErrCodeInvalidContentType,
+				fmt.Sprintf("need type of application/json, application/xml or application/x-www-form-urlencoded but got '%s'", r.Header.Get("content-type")), nil)
+			return
+		}
+		newResponse(w, r).WithStatus(http.StatusBadRequest).Error(ErrCodeBadRequest, err.Error(), nil)
+		return
 	}
+
 	h.Lock()
 	coasterBody.ID = fmt.Sprintf("%d", time.Now().UnixNano())
-	h.store[coasterBody.ID] = coasterBody
-	defer h.Unlock()
+	err := h.store.Put(coasterBody)
+	h.Unlock()
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+
+	newResponse(w, r).WithStatus(http.StatusCreated).JSON(coasterBody)
 }
 
 func (h *coasterHandlers) getRandomCoaster(w http.ResponseWriter, r *http.Request) {
-	ids := make([]string, len(h.store))
-
-	h.Lock()
-	i := 0
-	for id := range h.store {
-		ids[i] = id
-		i++
+	target, err := h.store.Random()
+	if errors.Is(err, ErrNotFound) {
+		newResponse(w, r).WithStatus(http.StatusNotFound).Error(ErrCodeNotFound, "no coasters to choose from", nil)
+		return
 	}
-	h.Unlock()
-
-	var target string
-	if len(ids) == 0 {
-		w.WriteHeader(http.StatusNotFound)
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
 		return
-	} else if len(ids) == 1 {
-		target = ids[0]
-	} else {
-		rand.Seed(time.Now().UnixNano())
-		target = ids[rand.Intn(len(ids)-1)]
 	}
 
 	w.Header().Add("content-type", "application/json")
-	w.Header().Add("location", fmt.Sprintf("/coasters/%s", target))
+	w.Header().Add("location", fmt.Sprintf("/coasters/%s", target.ID))
 	w.WriteHeader(http.StatusFound)
 }
 
-func (h *coasterHandlers) getCoaster(w http.ResponseWriter, r *http.Request) {
-	paths := strings.Split(r.URL.String(), "/")
-	if len(paths) != 3 {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("The url that you request is not found"))
+// coaster dispatches requests under /coasters/{id} by method. It replaces
+// the old single-purpose getCoaster with a router that also handles
+// PUT, PATCH and DELETE on the same path segment.
+func (h *coasterHandlers) coaster(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromPath(r.URL.Path)
+	if !ok {
+		newResponse(w, r).WithStatus(http.StatusNotFound).Error(ErrCodeNotFound, "the url that you request is not found", nil)
 		return
 	}
-	id := paths[2]
 
-	if id == "random" {
+	if id == "random" && r.Method == http.MethodGet {
+		if h.requireReadAuth && !hasRole(r, RoleUser) && !hasRole(r, RoleAdmin) {
+			forbidden(w, r)
+			return
+		}
 		h.getRandomCoaster(w, r)
 		return
 	}
 
+	switch r.Method {
+	case http.MethodGet:
+		if h.requireReadAuth && !hasRole(r, RoleUser) && !hasRole(r, RoleAdmin) {
+			forbidden(w, r)
+			return
+		}
+		h.getCoaster(w, r, id)
+	case http.MethodPut:
+		if !hasRole(r, RoleAdmin) {
+			forbidden(w, r)
+			return
+		}
+		h.putCoaster(w, r, id)
+	case http.MethodPatch:
+		if !hasRole(r, RoleAdmin) {
+			forbidden(w, r)
+			return
+		}
+		h.patchCoaster(w, r, id)
+	case http.MethodDelete:
+		if !hasRole(r, RoleAdmin) {
+			forbidden(w, r)
+			return
+		}
+		h.deleteCoaster(w, r, id)
+	default:
+		newResponse(w, r).WithStatus(http.StatusMethodNotAllowed).WithHeader("allow", "GET, PUT, PATCH, DELETE").
+			Error(ErrCodeMethodNotAllowed, "method not allowed", nil)
+	}
+}
+
+// idFromPath extracts the {id} segment from a /coasters/{id} path, rejecting
+// anything with extra segments or a missing id.
+func idFromPath(path string) (string, bool) {
+	paths := strings.Split(path, "/")
+	if len(paths) != 3 || paths[2] == "" {
+		return "", false
+	}
+	return paths[2], true
+}
+
+func (h *coasterHandlers) getCoaster(w http.ResponseWriter, r *http.Request, id string) {
 	h.Lock()
-	dataCoaster, ok := h.store[id]
+	dataCoaster, err := h.store.Get(id)
 	h.Unlock()
-	if !ok {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(fmt.Sprintf("No content found for ID, '%s'", id)))
+	if errors.Is(err, ErrNotFound) {
+		newResponse(w, r).WithStatus(http.StatusNotFound).Error(ErrCodeNotFound, fmt.Sprintf("no content found for ID, '%s'", id), nil)
+		return
+	}
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+
+	tag, err := etag(dataCoaster)
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+	if r.Header.Get("If-None-Match") == tag {
+		w.Header().Add("etag", tag)
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	jsonBytes, err := json.Marshal(dataCoaster)
+	w.Header().Add("etag", tag)
+	newResponse(w, r).WithStatus(http.StatusOK).JSON(dataCoaster)
+}
+
+// putCoaster fully replaces an existing coaster. If the caller supplies an
+// If-Match header that does not match the current ETag, the request is
+// rejected with 412 Precondition Failed; a request without If-Match
+// replaces the coaster unconditionally.
+func (h *coasterHandlers) putCoaster(w http.ResponseWriter, r *http.Request, id string) {
+	h.Lock()
+	defer h.Unlock()
+
+	existing, err := h.store.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		newResponse(w, r).WithStatus(http.StatusNotFound).Error(ErrCodeNotFound, fmt.Sprintf("no content found for ID, '%s'", id), nil)
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
-	w.Header().Add("content-type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(jsonBytes)
+	if ok := h.checkIfMatch(w, r, existing); !ok {
+		return
+	}
+
+	var replacement coaster
+	if err := Decode(r, &replacement); err != nil {
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			newResponse(w, r).WithStatus(http.StatusUnsupportedMediaType).Error(ErrCodeInvalidContentType,
+				fmt.Sprintf("need type of application/json, application/xml or application/x-www-form-urlencoded but got '%s'", r.Header.Get("content-type")), nil)
+			return
+		}
+		newResponse(w, r).WithStatus(http.StatusBadRequest).Error(ErrCodeBadRequest, err.Error(), nil)
+		return
+	}
+	replacement.ID = id
+	if err := h.store.Put(replacement); err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
 
+	h.writeCoasterLocked(w, r, replacement)
 }
 
-func (h *coasterHandlers) get(w http.ResponseWriter, r *http.Request) {
+// patchCoaster applies a JSON merge patch (RFC 7396): fields present in the
+// request body overwrite the stored value, fields absent are left alone.
+func (h *coasterHandlers) patchCoaster(w http.ResponseWriter, r *http.Request, id string) {
 	h.Lock()
-	coasters := make([]coaster, len(h.store))
-	i := 0
-	for _, coaster := range h.store {
-		coasters[i] = coaster
-		i++
+	defer h.Unlock()
+
+	existing, err := h.store.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		newResponse(w, r).WithStatus(http.StatusNotFound).Error(ErrCodeNotFound, fmt.Sprintf("no content found for ID, '%s'", id), nil)
+		return
 	}
-	h.Unlock()
-	jsonBytes, err := json.Marshal(coasters)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
 	}
-	w.Header().Add("content-type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(jsonBytes)
+
+	if ok := h.checkIfMatch(w, r, existing); !ok {
+		return
+	}
+
+	ct := mediaType(r.Header.Get("content-type"))
+	if ct != "application/json" && ct != "application/merge-patch+json" {
+		newResponse(w, r).WithStatus(http.StatusUnsupportedMediaType).Error(ErrCodeInvalidContentType,
+			fmt.Sprintf("need type of application/json (JSON merge patch) but got '%s'", ct), nil)
+		return
+	}
+
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+
+	merged, err := mergePatch(existing, bodyBytes)
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusBadRequest).Error(ErrCodeBadRequest, err.Error(), nil)
+		return
+	}
+	merged.ID = id
+	if err := h.store.Put(merged); err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+
+	h.writeCoasterLocked(w, r, merged)
 }
 
-func newCoasterHandlers() *coasterHandlers {
-	return &coasterHandlers{store: map[string]coaster{}}
+// mergePatch applies a JSON merge patch document onto an existing coaster,
+// per https://www.rfc-editor.org/rfc/rfc7396.
+func mergePatch(existing coaster, patch []byte) (coaster, error) {
+	existingBytes, err := json.Marshal(existing)
+	if err != nil {
+		return coaster{}, err
+	}
+
+	var merged map[string]interface{}
+	if err := json.Unmarshal(existingBytes, &merged); err != nil {
+		return coaster{}, err
+	}
+
+	var patchFields map[string]interface{}
+	if err := json.Unmarshal(patch, &patchFields); err != nil {
+		return coaster{}, err
+	}
+	for k, v := range patchFields {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+
+	mergedBytes, err := json.Marshal(merged)
+	if err != nil {
+		return coaster{}, err
+	}
+	var result coaster
+	if err := json.Unmarshal(mergedBytes, &result); err != nil {
+		return coaster{}, err
+	}
+	return result, nil
 }
 
-type adminPortal struct {
-	password string
+func (h *coasterHandlers) deleteCoaster(w http.ResponseWriter, r *http.Request, id string) {
+	h.Lock()
+	defer h.Unlock()
+
+	existing, err := h.store.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		newResponse(w, r).WithStatus(http.StatusNotFound).Error(ErrCodeNotFound, fmt.Sprintf("no content found for ID, '%s'", id), nil)
+		return
+	}
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+
+	if ok := h.checkIfMatch(w, r, existing); !ok {
+		return
+	}
+
+	if err := h.store.Delete(id); err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func newAdminPortal() *adminPortal {
-	password := os.Getenv("ADMIN_PASSWORD")
-	if password == "" {
-		panic("need to set env variable for ADMIN_PASSWORD")
+// checkIfMatch validates the request's If-Match header, if present, against
+// the current resource's ETag. It writes a 412 response and returns false
+// on mismatch; callers must stop processing in that case.
+func (h *coasterHandlers) checkIfMatch(w http.ResponseWriter, r *http.Request, existing coaster) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return true
 	}
-	return &adminPortal{password: password}
+	tag, err := etag(existing)
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return false
+	}
+	if ifMatch != tag {
+		newResponse(w, r).WithStatus(http.StatusPreconditionFailed).Error(ErrCodePreconditionFailed, "If-Match does not match the current ETag", nil)
+		return false
+	}
+	return true
 }
-func (a *adminPortal) handler(w http.ResponseWriter, r *http.Request) {
-	username, password, ok := r.BasicAuth()
-	if !ok || username != "admin" || password != a.password {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("401 unauthorized"))
+
+// writeCoasterLocked writes c as the JSON response body along with its
+// ETag. Callers must hold h's lock.
+func (h *coasterHandlers) writeCoasterLocked(w http.ResponseWriter, r *http.Request, c coaster) {
+	tag, err := etag(c)
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+	w.Header().Add("etag", tag)
+	newResponse(w, r).WithStatus(http.StatusOK).JSON(c)
+}
+
+// get handles GET /coasters, honoring ?park=, ?manufacturer=, ?minHeight=
+// and ?maxHeight= filters, ?sort=height|name&order=asc|desc, and
+// ?limit=&offset= pagination.
+func (h *coasterHandlers) get(w http.ResponseWriter, r *http.Request) {
+	var q coasterListQuery
+	if err := BindQuery(r.URL.Query(), &q); err != nil {
+		h.writeQueryError(w, r, err)
+		return
+	}
+	if err := q.validate(); err != nil {
+		h.writeQueryError(w, r, err)
 		return
 	}
+
+	h.Lock()
+	coasters, err := h.store.List()
+	h.Unlock()
+	if err != nil {
+		newResponse(w, r).WithStatus(http.StatusInternalServerError).Error(ErrCodeInternal, err.Error(), nil)
+		return
+	}
+
+	filtered := q.filter(coasters)
+	q.sortCoasters(filtered)
+	page, nextOffset, hasNext := q.paginate(filtered)
+
+	resp := coasterListResponse{Items: page, Total: len(filtered)}
+	if hasNext {
+		resp.Next = fmt.Sprintf("/coasters?%s", q.nextQueryString(nextOffset))
+	}
+
+	newResponse(w, r).WithStatus(http.StatusOK).JSON(resp)
+}
+
+func (h *coasterHandlers) writeQueryError(w http.ResponseWriter, r *http.Request, err error) {
+	newResponse(w, r).WithStatus(http.StatusBadRequest).Error(ErrCodeInvalidQuery, err.Error(), nil)
+}
+
+func newCoasterHandlers(store Store, requireReadAuth bool) *coasterHandlers {
+	return &coasterHandlers{store: store, requireReadAuth: requireReadAuth}
+}
+
+// adminPortal is now just a view; authentication and authorization are
+// handled by the middleware chain main wires it up behind.
+type adminPortal struct{}
+
+func newAdminPortal() *adminPortal {
+	return &adminPortal{}
+}
+
+func (a *adminPortal) handler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("<div><h2 style='color:orange;'>Wellcome, admin</h2></div>"))
 }
 
 func main() {
+	store, err := newStoreFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	if err := bootstrapAdminUser(store); err != nil {
+		panic(err)
+	}
+
+	auth, err := newAuthHandlers(store)
+	if err != nil {
+		panic(err)
+	}
+
+	requireReadAuth := os.Getenv("COASTERS_REQUIRE_READ_AUTH") == "true"
+	coasterHandler := newCoasterHandlers(store, requireReadAuth)
 	admin := newAdminPortal()
-	coasterHandler := newCoasterHandlers()
-	http.HandleFunc("/coasters", coasterHandler.coasters)
-	http.HandleFunc("/coasters/", coasterHandler.getCoaster)
-	http.HandleFunc("/admin", admin.handler)
+	metrics := newMetricsRegistry()
+
+	// observe wraps h (and any route-specific middleware such as auth) in
+	// the logging and metrics middleware shared by every route below.
+	// routePattern labels the metrics, not the literal request path, so
+	// per-resource traffic (e.g. /coasters/{id}) shares one series.
+	observe := func(routePattern string, h http.Handler, mws ...func(http.Handler) http.Handler) http.Handler {
+		return chain(h, append([]func(http.Handler) http.Handler{LoggingMiddleware, MetricsMiddleware(metrics, routePattern)}, mws...)...)
+	}
+
+	optionalAuth := OptionalBearerJWT(store, auth.secret)
+	mux := http.NewServeMux()
+	mux.Handle("/coasters", observe("/coasters", http.HandlerFunc(coasterHandler.coasters), optionalAuth))
+	mux.Handle("/coasters/", observe("/coasters/{id}", http.HandlerFunc(coasterHandler.coaster), optionalAuth))
+	mux.Handle("/auth/login", observe("/auth/login", http.HandlerFunc(auth.login)))
+	mux.Handle("/admin", observe("/admin", http.HandlerFunc(admin.handler), BearerJWT(store, auth.secret), RequireRole(RoleAdmin)))
+	mux.Handle("/metrics", observe("/metrics", metrics.handler()))
+
+	shutdownTimeout := defaultShutdownTimeout
+	if raw := os.Getenv("SHUTDOWN_TIMEOUT_SECONDS"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil {
+			panic(fmt.Errorf("invalid SHUTDOWN_TIMEOUT_SECONDS: %w", err))
+		}
+		shutdownTimeout = time.Duration(seconds) * time.Second
+	}
+
 	port := ":8080"
-	err := http.ListenAndServe(port, nil)
-	fmt.Println(fmt.Sprintf("Server running on http://localhost%s üêπ", port))
-	if err != nil {
+	srv := &http.Server{Addr: port, Handler: mux}
+	fmt.Println(fmt.Sprintf("Server running on http://localhost%s üêπ", port))
+	if err := run(srv, shutdownTimeout); err != nil {
 		panic(err)
 	}
 }
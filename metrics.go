@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultDurationBuckets are the histogram bucket bounds, in seconds, that
+// metricsRegistry reports http_request_duration_seconds against.
+var defaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// requestKey identifies one http_requests_total series.
+type requestKey struct {
+	method string
+	path   string
+	status int
+}
+
+// metricsRegistry accumulates the counters and histogram this API exposes
+// at /metrics, in Prometheus text exposition format.
+type metricsRegistry struct {
+	mu          sync.Mutex
+	requests    map[requestKey]int64
+	buckets     []float64
+	bucketCnt   map[float64]uint64
+	durationSum float64
+	durationCnt uint64
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		requests:  make(map[requestKey]int64),
+		buckets:   defaultDurationBuckets,
+		bucketCnt: make(map[float64]uint64),
+	}
+}
+
+// observe records one completed request: method/path/status for the
+// counter, and duration for the histogram.
+func (m *metricsRegistry) observe(method, path string, status int, duration time.Duration) {
+	seconds := duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{method: method, path: path, status: status}]++
+	m.durationSum += seconds
+	m.durationCnt++
+	for _, bound := range m.buckets {
+		if seconds <= bound {
+			m.bucketCnt[bound]++
+		}
+	}
+}
+
+// MetricsMiddleware records every request that passes through it against
+// reg after it completes, labeling it with routePattern (the registered
+// mux pattern, e.g. "/coasters/{id}") rather than the literal request path.
+// Path-based labels would give every distinct coaster ID (and any junk
+// 404 path) its own permanent series, growing reg without bound.
+func MetricsMiddleware(reg *metricsRegistry, routePattern string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r)
+			reg.observe(r.Method, routePattern, rec.status, time.Since(start))
+		})
+	}
+}
+
+// handler renders reg as the /metrics endpoint, in Prometheus text
+// exposition format.
+func (m *metricsRegistry) handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		w.Header().Set("content-type", "text/plain; version=0.0.4")
+
+		fmt.Fprintln(w, "# HELP http_requests_total Total number of HTTP requests.")
+		fmt.Fprintln(w, "# TYPE http_requests_total counter")
+		keys := make([]requestKey, 0, len(m.requests))
+		for k := range m.requests {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].path != keys[j].path {
+				return keys[i].path < keys[j].path
+			}
+			if keys[i].method != keys[j].method {
+				return keys[i].method < keys[j].method
+			}
+			return keys[i].status < keys[j].status
+		})
+		for _, k := range keys {
+			fmt.Fprintf(w, "http_requests_total{method=%q,path=%q,status=%q} %d\n",
+				k.method, k.path, strconv.Itoa(k.status), m.requests[k])
+		}
+
+		fmt.Fprintln(w, "# HELP http_request_duration_seconds Duration of HTTP requests in seconds.")
+		fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+		// bucketCnt[bound] already counts every observation <= bound (see
+		// observe), so each le bucket is printed as-is rather than summed.
+		for _, bound := range m.buckets {
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(bound, 'f', -1, 64), m.bucketCnt[bound])
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.durationCnt)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum %s\n", strconv.FormatFloat(m.durationSum, 'f', -1, 64))
+		fmt.Fprintf(w, "http_request_duration_seconds_count %d\n", m.durationCnt)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsRegistryObserveAndRender(t *testing.T) {
+	reg := newMetricsRegistry()
+	reg.observe("GET", "/coasters", 200, 2*time.Millisecond)
+	reg.observe("GET", "/coasters", 200, 2*time.Millisecond)
+	reg.observe("GET", "/coasters/1", 404, 20*time.Second)
+
+	w := httptest.NewRecorder()
+	reg.handler()(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := w.Body.String()
+	if !strings.Contains(body, `http_requests_total{method="GET",path="/coasters",status="200"} 2`) {
+		t.Fatalf("missing /coasters counter line, body:\n%s", body)
+	}
+	if !strings.Contains(body, `http_requests_total{method="GET",path="/coasters/1",status="404"} 1`) {
+		t.Fatalf("missing /coasters/1 counter line, body:\n%s", body)
+	}
+	if !strings.Contains(body, `http_request_duration_seconds_bucket{le="0.005"} 2`) {
+		t.Fatalf("want 2 observations <= 5ms bucket, body:\n%s", body)
+	}
+	if !strings.Contains(body, `http_request_duration_seconds_bucket{le="+Inf"} 3`) {
+		t.Fatalf("want 3 total observations in +Inf bucket, body:\n%s", body)
+	}
+	if !strings.Contains(body, "http_request_duration_seconds_count 3") {
+		t.Fatalf("want count 3, body:\n%s", body)
+	}
+}
+
+// TestMetricsMiddlewareLabelsByRoutePattern guards against the literal
+// request path leaking into the counter's labels: distinct coaster IDs
+// must collapse onto one "/coasters/{id}" series, not one series each.
+func TestMetricsMiddlewareLabelsByRoutePattern(t *testing.T) {
+	reg := newMetricsRegistry()
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := MetricsMiddleware(reg, "/coasters/{id}")(noop)
+
+	mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/coasters/1", nil))
+	mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/coasters/2", nil))
+
+	if got := len(reg.requests); got != 1 {
+		t.Fatalf("len(reg.requests) = %d, want 1 (one series for the route pattern)", got)
+	}
+	key := requestKey{method: http.MethodGet, path: "/coasters/{id}", status: http.StatusOK}
+	if reg.requests[key] != 2 {
+		t.Fatalf("reg.requests[%+v] = %d, want 2", key, reg.requests[key])
+	}
+}
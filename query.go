@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// coasterListResponse envelopes a GET /coasters page with the pagination
+// metadata callers need to fetch the next one.
+type coasterListResponse struct {
+	XMLName xml.Name  `json:"-" xml:"coasters"`
+	Items   []coaster `json:"items" xml:"item"`
+	Next    string    `json:"next,omitempty" xml:"next,omitempty"`
+	Total   int       `json:"total" xml:"total"`
+}
+
+// BindQuery binds url.Values into the struct pointed to by v, matching
+// query parameter names against each field's `query` tag the way common Go
+// web binders bind query and form parameters. Supported field kinds are
+// string, int-family, and pointers to either; a pointer field is left nil
+// when its parameter is absent, so callers can distinguish "not set" from
+// the zero value.
+func BindQuery(values url.Values, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("query: Bind target must be a pointer to a struct")
+	}
+
+	elem := rv.Elem()
+	t := elem.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("query")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+
+		if err := setQueryField(elem.Field(i), name, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setQueryField(fv reflect.Value, name, raw string) error {
+	if fv.Kind() == reflect.Ptr {
+		fv.Set(reflect.New(fv.Type().Elem()))
+		fv = fv.Elem()
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("query: field %q: %w", name, err)
+		}
+		fv.SetInt(n)
+	default:
+		return fmt.Errorf("query: unsupported field kind %s for %q", fv.Kind(), name)
+	}
+	return nil
+}
+
+// coasterListQuery captures the query parameters GET /coasters understands:
+// park/manufacturer/minHeight/maxHeight for filtering, sort/order for
+// ordering, and limit/offset for pagination.
+type coasterListQuery struct {
+	Park         string `query:"park"`
+	Manufacturer string `query:"manufacturer"`
+	MinHeight    *int   `query:"minHeight"`
+	MaxHeight    *int   `query:"maxHeight"`
+	Sort         string `query:"sort"`
+	Order        string `query:"order"`
+	Limit        *int   `query:"limit"`
+	Offset       *int   `query:"offset"`
+}
+
+func (q coasterListQuery) validate() error {
+	switch q.Sort {
+	case "", "height", "name":
+	default:
+		return fmt.Errorf("sort must be 'height' or 'name', got %q", q.Sort)
+	}
+	switch q.Order {
+	case "", "asc", "desc":
+	default:
+		return fmt.Errorf("order must be 'asc' or 'desc', got %q", q.Order)
+	}
+	if q.Limit != nil && *q.Limit <= 0 {
+		return fmt.Errorf("limit must be greater than zero")
+	}
+	if q.Offset != nil && *q.Offset < 0 {
+		return fmt.Errorf("offset must not be negative")
+	}
+	if q.MinHeight != nil && q.MaxHeight != nil && *q.MinHeight > *q.MaxHeight {
+		return fmt.Errorf("minHeight must not be greater than maxHeight")
+	}
+	return nil
+}
+
+func (q coasterListQuery) filter(coasters []coaster) []coaster {
+	out := make([]coaster, 0, len(coasters))
+	for _, c := range coasters {
+		if q.Park != "" && c.InPark != q.Park {
+			continue
+		}
+		if q.Manufacturer != "" && c.Manufactur != q.Manufacturer {
+			continue
+		}
+		if q.MinHeight != nil && c.Height < *q.MinHeight {
+			continue
+		}
+		if q.MaxHeight != nil && c.Height > *q.MaxHeight {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (q coasterListQuery) sortCoasters(coasters []coaster) {
+	if q.Sort == "" {
+		return
+	}
+	less := func(i, j int) bool {
+		switch q.Sort {
+		case "height":
+			return coasters[i].Height < coasters[j].Height
+		default: // "name"
+			return coasters[i].Name < coasters[j].Name
+		}
+	}
+	if q.Order == "desc" {
+		sort.SliceStable(coasters, func(i, j int) bool { return less(j, i) })
+		return
+	}
+	sort.SliceStable(coasters, less)
+}
+
+// paginate slices coasters to the requested page, returning the offset of
+// the next page and whether one exists.
+func (q coasterListQuery) paginate(coasters []coaster) (page []coaster, nextOffset int, hasNext bool) {
+	offset := 0
+	if q.Offset != nil {
+		offset = *q.Offset
+	}
+	if offset > len(coasters) {
+		offset = len(coasters)
+	}
+
+	end := len(coasters)
+	if q.Limit != nil {
+		if limited := offset + *q.Limit; limited < end {
+			end = limited
+		}
+	}
+
+	page = coasters[offset:end]
+	if end < len(coasters) {
+		return page, end, true
+	}
+	return page, 0, false
+}
+
+// nextQueryString builds the query string for the envelope's next URL,
+// preserving every filter and sort parameter but advancing the offset.
+func (q coasterListQuery) nextQueryString(nextOffset int) string {
+	values := url.Values{}
+	if q.Park != "" {
+		values.Set("park", q.Park)
+	}
+	if q.Manufacturer != "" {
+		values.Set("manufacturer", q.Manufacturer)
+	}
+	if q.MinHeight != nil {
+		values.Set("minHeight", strconv.Itoa(*q.MinHeight))
+	}
+	if q.MaxHeight != nil {
+		values.Set("maxHeight", strconv.Itoa(*q.MaxHeight))
+	}
+	if q.Sort != "" {
+		values.Set("sort", q.Sort)
+	}
+	if q.Order != "" {
+		values.Set("order", q.Order)
+	}
+	if q.Limit != nil {
+		values.Set("limit", strconv.Itoa(*q.Limit))
+	}
+	values.Set("offset", strconv.Itoa(nextOffset))
+	return values.Encode()
+}
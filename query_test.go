@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestBindQuery(t *testing.T) {
+	values, err := url.ParseQuery("park=Carowinds&minHeight=50&sort=height&order=desc")
+	if err != nil {
+		t.Fatalf("ParseQuery: %v", err)
+	}
+
+	var q coasterListQuery
+	if err := BindQuery(values, &q); err != nil {
+		t.Fatalf("BindQuery: %v", err)
+	}
+
+	if q.Park != "Carowinds" || q.Sort != "height" || q.Order != "desc" {
+		t.Fatalf("BindQuery() = %+v, unexpected string fields", q)
+	}
+	if q.MinHeight == nil || *q.MinHeight != 50 {
+		t.Fatalf("BindQuery() MinHeight = %v, want 50", q.MinHeight)
+	}
+	if q.MaxHeight != nil {
+		t.Fatalf("BindQuery() MaxHeight = %v, want nil", q.MaxHeight)
+	}
+}
+
+func TestCoasterListQueryFilterSortPaginate(t *testing.T) {
+	coasters := []coaster{
+		{ID: "1", Name: "Fury 325", Manufactur: "B&M", InPark: "Carowinds", Height: 99},
+		{ID: "2", Name: "Orion", Manufactur: "B&M", InPark: "Kings Island", Height: 87},
+		{ID: "3", Name: "Steel Vengeance", Manufactur: "RMC", InPark: "Cedar Point", Height: 62},
+	}
+
+	minHeight := 70
+	q := coasterListQuery{Manufacturer: "B&M", MinHeight: &minHeight, Sort: "name", Order: "asc"}
+	if err := q.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	filtered := q.filter(coasters)
+	q.sortCoasters(filtered)
+	var names []string
+	for _, c := range filtered {
+		names = append(names, c.Name)
+	}
+	if want := []string{"Fury 325", "Orion"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("filter+sort names = %v, want %v", names, want)
+	}
+
+	limit := 1
+	q.Limit = &limit
+	page, next, hasNext := q.paginate(filtered)
+	if len(page) != 1 || page[0].Name != "Fury 325" {
+		t.Fatalf("paginate page = %+v, want [Fury 325]", page)
+	}
+	if !hasNext || next != 1 {
+		t.Fatalf("paginate next = %d, hasNext = %v, want 1, true", next, hasNext)
+	}
+
+	offset := 1
+	q.Offset = &offset
+	page, _, hasNext = q.paginate(filtered)
+	if len(page) != 1 || page[0].Name != "Orion" {
+		t.Fatalf("paginate page at offset 1 = %+v, want [Orion]", page)
+	}
+	if hasNext {
+		t.Fatalf("paginate hasNext at the last page = true, want false")
+	}
+}
+
+func TestCoasterListQueryValidate(t *testing.T) {
+	bad := coasterListQuery{Sort: "length"}
+	if err := bad.validate(); err == nil {
+		t.Fatalf("validate() with bad sort = nil, want error")
+	}
+
+	negative := -1
+	bad = coasterListQuery{Limit: &negative}
+	if err := bad.validate(); err == nil {
+		t.Fatalf("validate() with negative limit = nil, want error")
+	}
+
+	zero := 0
+	bad = coasterListQuery{Limit: &zero}
+	if err := bad.validate(); err == nil {
+		t.Fatalf("validate() with zero limit = nil, want error")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout bounds how long run waits for in-flight requests
+// to finish draining after a shutdown signal, overridable via
+// SHUTDOWN_TIMEOUT_SECONDS.
+const defaultShutdownTimeout = 10 * time.Second
+
+// run starts srv and blocks until it exits: either because ListenAndServe
+// failed, or because a SIGINT/SIGTERM arrived, in which case it gives
+// in-flight requests up to shutdownTimeout to finish before returning.
+func run(srv *http.Server, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case sig := <-sigCh:
+		fmt.Printf("received %s, shutting down\n", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNotFound is returned by Store implementations when the requested
+// coaster or user does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Store is the persistence contract coasterHandlers and authHandlers
+// depend on. Every implementation must be safe for concurrent use.
+type Store interface {
+	Get(id string) (coaster, error)
+	List() ([]coaster, error)
+	Put(c coaster) error
+	Delete(id string) error
+	Random() (coaster, error)
+
+	GetUser(username string) (User, error)
+	PutUser(u User) error
+}
+
+// newStoreFromEnv selects a Store implementation based on STORE_BACKEND:
+// "memory" (the default), "sqlite" (path from SQLITE_PATH, default
+// "coasters.db"), or "file" (path from FILE_STORE_PATH, default
+// "coasters.json").
+func newStoreFromEnv() (Store, error) {
+	switch backend := os.Getenv("STORE_BACKEND"); backend {
+	case "", "memory":
+		return newMemoryStore(), nil
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "coasters.db"
+		}
+		return newSQLiteStore(path)
+	case "file":
+		path := os.Getenv("FILE_STORE_PATH")
+		if path == "" {
+			path = "coasters.json"
+		}
+		return newFileStore(path)
+	default:
+		return nil, fmt.Errorf("unknown STORE_BACKEND %q", backend)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileData is the on-disk shape of a fileStore: the whole collection in one
+// JSON document.
+type fileData struct {
+	Coasters map[string]coaster `json:"coasters"`
+	Users    map[string]User    `json:"users"`
+}
+
+func newFileData() fileData {
+	return fileData{Coasters: map[string]coaster{}, Users: map[string]User{}}
+}
+
+// fileStore persists the whole coaster and user collection as a single
+// JSON file. Every write goes to a temp file in the same directory first
+// and is then renamed into place, so a crash mid-write never leaves a
+// corrupt file on disk.
+type fileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	s := &fileStore{path: path}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := s.writeLocked(newFileData()); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileStore) readLocked() (fileData, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return fileData{}, err
+	}
+	data := newFileData()
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &data); err != nil {
+			return fileData{}, err
+		}
+	}
+	return data, nil
+}
+
+func (s *fileStore) writeLocked(data fileData) error {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	tmp, err := os.CreateTemp(dir, ".coasters-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func (s *fileStore) Get(id string) (coaster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.readLocked()
+	if err != nil {
+		return coaster{}, err
+	}
+	c, ok := data.Coasters[id]
+	if !ok {
+		return coaster{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (s *fileStore) List() ([]coaster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.readLocked()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]coaster, 0, len(data.Coasters))
+	for _, c := range data.Coasters {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (s *fileStore) Put(c coaster) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	data.Coasters[c.ID] = c
+	return s.writeLocked(data)
+}
+
+func (s *fileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	if _, ok := data.Coasters[id]; !ok {
+		return ErrNotFound
+	}
+	delete(data.Coasters, id)
+	return s.writeLocked(data)
+}
+
+func (s *fileStore) Random() (coaster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.readLocked()
+	if err != nil {
+		return coaster{}, err
+	}
+	if len(data.Coasters) == 0 {
+		return coaster{}, ErrNotFound
+	}
+	ids := make([]string, 0, len(data.Coasters))
+	for id := range data.Coasters {
+		ids = append(ids, id)
+	}
+	rand.Seed(time.Now().UnixNano())
+	return data.Coasters[ids[rand.Intn(len(ids))]], nil
+}
+
+func (s *fileStore) GetUser(username string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.readLocked()
+	if err != nil {
+		return User{}, err
+	}
+	u, ok := data.Users[username]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *fileStore) PutUser(u User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	data.Users[u.Username] = u
+	return s.writeLocked(data)
+}
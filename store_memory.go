@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// memoryStore is the original in-memory, non-persistent Store
+// implementation: data is lost on restart.
+type memoryStore struct {
+	mu    sync.Mutex
+	items map[string]coaster
+	users map[string]User
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{items: map[string]coaster{}, users: map[string]User{}}
+}
+
+func (s *memoryStore) Get(id string) (coaster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.items[id]
+	if !ok {
+		return coaster{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (s *memoryStore) List() ([]coaster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]coaster, 0, len(s.items))
+	for _, c := range s.items {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (s *memoryStore) Put(c coaster) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[c.ID] = c
+	return nil
+}
+
+func (s *memoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.items, id)
+	return nil
+}
+
+func (s *memoryStore) Random() (coaster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.items) == 0 {
+		return coaster{}, ErrNotFound
+	}
+	ids := make([]string, 0, len(s.items))
+	for id := range s.items {
+		ids = append(ids, id)
+	}
+	rand.Seed(time.Now().UnixNano())
+	return s.items[ids[rand.Intn(len(ids))]], nil
+}
+
+func (s *memoryStore) GetUser(username string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[username]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *memoryStore) PutUser(u User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.Username] = u
+	return nil
+}
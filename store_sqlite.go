@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS coasters (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	manufactur TEXT NOT NULL,
+	in_park    TEXT NOT NULL,
+	height     INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS users (
+	username      TEXT PRIMARY KEY,
+	password_hash TEXT NOT NULL,
+	role          TEXT NOT NULL
+);`
+
+// sqliteStore is a Store backed by SQLite, with the schema migrated on
+// startup so the table always exists before it is queried.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite store: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return nil, fmt.Errorf("migrate sqlite store: %w", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Get(id string) (coaster, error) {
+	row := s.db.QueryRow(`SELECT id, name, manufactur, in_park, height FROM coasters WHERE id = ?`, id)
+	var c coaster
+	if err := row.Scan(&c.ID, &c.Name, &c.Manufactur, &c.InPark, &c.Height); err != nil {
+		if err == sql.ErrNoRows {
+			return coaster{}, ErrNotFound
+		}
+		return coaster{}, err
+	}
+	return c, nil
+}
+
+func (s *sqliteStore) GetUser(username string) (User, error) {
+	row := s.db.QueryRow(`SELECT username, password_hash, role FROM users WHERE username = ?`, username)
+	var u User
+	if err := row.Scan(&u.Username, &u.PasswordHash, &u.Role); err != nil {
+		if err == sql.ErrNoRows {
+			return User{}, ErrNotFound
+		}
+		return User{}, err
+	}
+	return u, nil
+}
+
+func (s *sqliteStore) PutUser(u User) error {
+	_, err := s.db.Exec(`
+		INSERT INTO users (username, password_hash, role)
+		VALUES (?, ?, ?)
+		ON CONFLICT(username) DO UPDATE SET
+			password_hash = excluded.password_hash,
+			role = excluded.role`,
+		u.Username, u.PasswordHash, u.Role)
+	return err
+}
+
+func (s *sqliteStore) List() ([]coaster, error) {
+	rows, err := s.db.Query(`SELECT id, name, manufactur, in_park, height FROM coasters`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []coaster
+	for rows.Next() {
+		var c coaster
+		if err := rows.Scan(&c.ID, &c.Name, &c.Manufactur, &c.InPark, &c.Height); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqliteStore) Put(c coaster) error {
+	_, err := s.db.Exec(`
+		INSERT INTO coasters (id, name, manufactur, in_park, height)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			manufactur = excluded.manufactur,
+			in_park = excluded.in_park,
+			height = excluded.height`,
+		c.ID, c.Name, c.Manufactur, c.InPark, c.Height)
+	return err
+}
+
+func (s *sqliteStore) Delete(id string) error {
+	res, err := s.db.Exec(`DELETE FROM coasters WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqliteStore) Random() (coaster, error) {
+	row := s.db.QueryRow(`SELECT id, name, manufactur, in_park, height FROM coasters ORDER BY RANDOM() LIMIT 1`)
+	var c coaster
+	if err := row.Scan(&c.ID, &c.Name, &c.Manufactur, &c.InPark, &c.Height); err != nil {
+		if err == sql.ErrNoRows {
+			return coaster{}, ErrNotFound
+		}
+		return coaster{}, err
+	}
+	return c, nil
+}
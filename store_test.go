@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// storeFactories enumerates the Store implementations that are exercised
+// against the shared contract below. The SQLite backend is intentionally
+// left out here since it needs cgo and the sqlite3 driver; it is covered
+// manually against a real database.
+func storeFactories(t *testing.T) map[string]func() Store {
+	return map[string]func() Store{
+		"memory": func() Store {
+			return newMemoryStore()
+		},
+		"file": func() Store {
+			path := filepath.Join(t.TempDir(), "coasters.json")
+			s, err := newFileStore(path)
+			if err != nil {
+				t.Fatalf("newFileStore: %v", err)
+			}
+			return s
+		},
+	}
+}
+
+func TestStoreContract(t *testing.T) {
+	for name, factory := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			s := factory()
+
+			if _, err := s.Get("missing"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get(missing) = %v, want ErrNotFound", err)
+			}
+			if _, err := s.Random(); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Random() on empty store = %v, want ErrNotFound", err)
+			}
+			if err := s.Delete("missing"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Delete(missing) = %v, want ErrNotFound", err)
+			}
+
+			want := coaster{ID: "1", Name: "Fury 325", Manufactur: "B&M", InPark: "Carowinds", Height: 99}
+			if err := s.Put(want); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			got, err := s.Get("1")
+			if err != nil {
+				t.Fatalf("Get(1): %v", err)
+			}
+			if got != want {
+				t.Fatalf("Get(1) = %+v, want %+v", got, want)
+			}
+
+			list, err := s.List()
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(list) != 1 || list[0] != want {
+				t.Fatalf("List() = %+v, want [%+v]", list, want)
+			}
+
+			random, err := s.Random()
+			if err != nil {
+				t.Fatalf("Random: %v", err)
+			}
+			if random != want {
+				t.Fatalf("Random() = %+v, want %+v", random, want)
+			}
+
+			updated := want
+			updated.Height = 100
+			if err := s.Put(updated); err != nil {
+				t.Fatalf("Put (update): %v", err)
+			}
+			if got, err := s.Get("1"); err != nil || got != updated {
+				t.Fatalf("Get(1) after update = %+v, %v, want %+v", got, err, updated)
+			}
+
+			if err := s.Delete("1"); err != nil {
+				t.Fatalf("Delete(1): %v", err)
+			}
+			if _, err := s.Get("1"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get(1) after delete = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestUserStoreContract(t *testing.T) {
+	for name, factory := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			s := factory()
+
+			if _, err := s.GetUser("alice"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("GetUser(alice) = %v, want ErrNotFound", err)
+			}
+
+			want := User{Username: "alice", PasswordHash: "hash", Role: RoleAdmin}
+			if err := s.PutUser(want); err != nil {
+				t.Fatalf("PutUser: %v", err)
+			}
+
+			got, err := s.GetUser("alice")
+			if err != nil {
+				t.Fatalf("GetUser(alice): %v", err)
+			}
+			if got != want {
+				t.Fatalf("GetUser(alice) = %+v, want %+v", got, want)
+			}
+
+			updated := want
+			updated.Role = RoleUser
+			if err := s.PutUser(updated); err != nil {
+				t.Fatalf("PutUser (update): %v", err)
+			}
+			if got, err := s.GetUser("alice"); err != nil || got != updated {
+				t.Fatalf("GetUser(alice) after update = %+v, %v, want %+v", got, err, updated)
+			}
+		})
+	}
+}
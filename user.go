@@ -0,0 +1,16 @@
+package main
+
+// Permission roles recognized by RequireRole.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// User is an authenticated principal. PasswordHash is a bcrypt hash; no
+// handler ever sends a User back to a client, so there is no separate
+// wire representation to strip it from.
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Role         string `json:"role"`
+}